@@ -3,29 +3,42 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
 	"gopkg.in/yaml.v2"
 	"k8s.io/klog/v2"
+
+	"github.com/tstromberg/krunk/pkg/backoff"
+	"github.com/tstromberg/krunk/pkg/event"
+	"github.com/tstromberg/krunk/pkg/exit"
+	"github.com/tstromberg/krunk/pkg/probe"
+	"github.com/tstromberg/krunk/pkg/reason"
+	"github.com/tstromberg/krunk/pkg/runner"
+	"github.com/tstromberg/krunk/pkg/scheduler"
 )
 
 var targetFlag = flag.String("target", "minikube", "What kind of cluster to target: kind, minikube, k3d, remote")
 var sceneFlag = flag.String("scene", "", "configuration file to load test cases from")
 var timeoutFlag = flag.Duration("timeout", 6*time.Minute, "maximum time a command can take")
-
-// Requirements describes the requirements of the target cluster
-type Requirements struct {
-	KubernetesVersion string `yaml:"kubernetes-version"`
-	ControlPlanes     int
-	Workers           int
-	CNI               string
-}
+var remoteHostFlag = flag.String("remote-host", "", "hostname or IP of the remote control-plane node (--target=remote only)")
+var remoteUserFlag = flag.String("remote-user", "", "SSH user to connect as (--target=remote only)")
+var remoteKeyFlag = flag.String("remote-key", "", "path to an SSH private key (--target=remote only)")
+var remoteWorkersFlag = flag.String("remote-workers", "", "comma-separated hostnames of remote worker nodes (--target=remote only)")
+var outputFlag = flag.String("output", "text", "output format: text (klog) or json (one event object per line on stdout)")
+var parallelismFlag = flag.Int("parallelism", 4, "maximum number of independent steps to run at once")
+var teardownFlag = flag.Bool("teardown", false, "delete the cluster on exit, including after Ctrl-C")
 
 type Xfer struct {
 	Source string
@@ -35,33 +48,261 @@ type Xfer struct {
 
 type Step struct {
 	Local        string
+	Stdin        string
 	Transfer     Xfer
 	ControlPlane string `yaml:"control-plane"`
 	Worker       string
-	Background   bool
+	// Nodes names an explicit set of nodes to run Local on.
+	Nodes []string
+	// NodeSelector matches a set of nodes to run Local on, e.g.
+	// "all-workers" or "role=worker,index=1". See runner.SelectNodes.
+	NodeSelector string `yaml:"node-selector"`
+	// AllowFailures lets the step succeed even if some matched nodes fail,
+	// as long as at least one succeeded.
+	AllowFailures bool `yaml:"allow-failures"`
+	Background    bool
+
+	// Name lets other steps reference this one in DependsOn. Steps
+	// without a Name can still run, but nothing can depend on them.
+	Name string
+	// DependsOn lists step Names that must finish successfully before
+	// this step starts. Independent steps run concurrently, up to
+	// --parallelism.
+	DependsOn []string `yaml:"depends-on"`
+
+	// Probe, if set, is checked after the step's command runs, on the
+	// schedule described by Retry, until it's satisfied, Retry.Attempts
+	// checks have been made (a single check if Attempts is unset), or the
+	// step timeout expires.
+	Probe probe.Probe
+	Retry backoff.Config
 }
 
 type Scenario struct {
-	Requirements Requirements
+	Requirements runner.Requirements
 	Setup        []Step
+	// Assertions run once Setup completes, to check that the scene
+	// actually reached the state it was meant to demonstrate.
+	Assertions []Assertion
+}
+
+// Assertion checks one expected (or forbidden) outcome of a scene.
+type Assertion struct {
+	Name  string
+	Probe probe.Probe
+	// Within bounds how long to wait for Probe to settle. Defaults to
+	// --timeout.
+	Within time.Duration
+	// MustNot inverts the assertion: it passes only if Probe never
+	// succeeds within Within. Absent MustNot, the assertion requires
+	// Probe to succeed at least once within Within (i.e. "Must").
+	MustNot bool `yaml:"must-not"`
 }
 
-// RunResult stores the result of an cmd.Run call
-type RunResult struct {
-	Stdout   *bytes.Buffer
-	Stderr   *bytes.Buffer
-	ExitCode int
+// stepError pairs the reason.Kind a failure should be reported under with
+// the error that produced it. scheduler.Run wraps and rewraps errors as
+// they propagate (per-node, then out of Run itself), so the typed Kind
+// would otherwise be lost by the time main sees it; errors.As can recover
+// it through any number of %w wraps as long as each preserves Unwrap.
+type stepError struct {
+	kind reason.Kind
+	err  error
+}
+
+func (e *stepError) Error() string { return fmt.Sprintf("%s: %s", e.kind.ID, e.err) }
+func (e *stepError) Unwrap() error { return e.err }
+
+// runStep executes a single step, returning the RunResult of the command
+// that ran and the reason.Kind that should be reported if err is fatal.
+// For steps that fan out across multiple nodes, no single RunResult
+// applies, so rr is nil even on success.
+func runStep(parent context.Context, r runner.Runner, s Step, d time.Duration) (rr *runner.RunResult, k reason.Kind, err error) {
+	ctx, cancel := context.WithTimeout(parent, d)
+	defer cancel()
+
+	var stdin io.Reader
+	if s.Stdin != "" {
+		stdin = strings.NewReader(s.Stdin)
+	}
+
+	klog.V(1).Infof("STEP: %+v", s)
+	switch {
+	case len(s.Nodes) > 0 || s.NodeSelector != "":
+		nodes, nerr := resolveNodes(ctx, r, s)
+		if nerr != nil {
+			return nil, reason.StepSSH, nerr
+		}
+		rr, k, err = nil, reason.StepSSH, runOnNodes(ctx, r, nodes, s)
+	case s.ControlPlane != "":
+		rr, err = r.RunOn(ctx, s.ControlPlane, s.Local, stdin)
+		k = reason.StepSSH
+	case s.Worker != "":
+		rr, err = r.RunOn(ctx, s.Worker, s.Local, stdin)
+		k = reason.StepSSH
+	case s.Transfer.Source != "":
+		t := s.Transfer
+		err = r.Copy(ctx, t.Target, t.Source, t.Dest)
+		k = reason.StepTransfer
+	case s.Local != "":
+		rr, err = runLocal(exec.CommandContext(ctx, "sh", "-c", s.Local), stdin)
+		k = reason.StepLocal
+	default:
+		k = reason.StepLocal
+	}
+
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		k = reason.TimeoutExceeded
+	}
+	if err != nil || s.Probe.IsZero() {
+		return rr, k, err
+	}
+
+	if perr := backoff.Retry(ctx, s.Retry, func() error { return s.Probe.Check(ctx, r) }); perr != nil {
+		return rr, reason.TimeoutExceeded, fmt.Errorf("probe never succeeded: %w", perr)
+	}
+	return rr, k, nil
+}
+
+// resolveNodes turns a Step's Nodes/NodeSelector into a concrete node
+// name list, consulting the cluster when a selector is used.
+func resolveNodes(ctx context.Context, r runner.Runner, s Step) ([]string, error) {
+	if len(s.Nodes) > 0 {
+		return s.Nodes, nil
+	}
+	all, err := r.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
+	matched, err := runner.SelectNodes(all, s.NodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(matched))
+	for _, n := range matched {
+		names = append(names, n.Name)
+	}
+	return names, nil
+}
+
+// runOnNodes fans s.Local out across nodes in parallel, waiting for all of
+// them to finish. It fails the step if any node fails, unless
+// s.AllowFailures is set and at least one node succeeded.
+func runOnNodes(ctx context.Context, r runner.Runner, nodes []string, s Step) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(nodes))
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node string) {
+			defer wg.Done()
+			_, err := r.RunOn(ctx, node, s.Local, nil)
+			errs[i] = err
+		}(i, node)
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			klog.Errorf("node %s failed: %v", nodes[i], err)
+			failed = append(failed, nodes[i])
+		}
+	}
+	// AllowFailures tolerates some nodes failing, but not all of them: the
+	// step must still have been attempted successfully somewhere.
+	if len(failed) > 0 && (!s.AllowFailures || len(failed) == len(nodes)) {
+		return fmt.Errorf("%d of %d nodes failed: %s", len(failed), len(nodes), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// assertionResult is one row of the PASS/FAIL summary table.
+type assertionResult struct {
+	Name     string
+	Pass     bool
 	Duration time.Duration
-	Args     []string
+	Err      error
+}
+
+// runAssertions checks every assertion concurrently and returns one
+// result per assertion, in the same order.
+func runAssertions(ctx context.Context, r runner.Runner, assertions []Assertion, defaultWithin time.Duration) []assertionResult {
+	results := make([]assertionResult, len(assertions))
+	var wg sync.WaitGroup
+	for i, a := range assertions {
+		wg.Add(1)
+		go func(i int, a Assertion) {
+			defer wg.Done()
+			pass, dur, err := runAssertion(ctx, r, a, defaultWithin)
+			results[i] = assertionResult{Name: a.Name, Pass: pass, Duration: dur, Err: err}
+		}(i, a)
+	}
+	wg.Wait()
+	return results
 }
 
-// Run is a helper to log command execution
-func Run(cmd *exec.Cmd) (*RunResult, error) {
-	rr := &RunResult{Args: cmd.Args}
+// assertionPollInterval is how often a MustNot assertion re-checks that
+// its probe still hasn't succeeded.
+const assertionPollInterval = time.Second
+
+// runAssertion waits up to a.Within (or defaultWithin) for a.Probe to
+// reach the state a.MustNot calls for.
+func runAssertion(ctx context.Context, r runner.Runner, a Assertion, defaultWithin time.Duration) (pass bool, dur time.Duration, err error) {
+	within := a.Within
+	if within <= 0 {
+		within = defaultWithin
+	}
+	ctx, cancel := context.WithTimeout(ctx, within)
+	defer cancel()
+
+	start := time.Now()
+	if a.MustNot {
+		ticker := time.NewTicker(assertionPollInterval)
+		defer ticker.Stop()
+		for {
+			if perr := a.Probe.Check(ctx, r); perr == nil {
+				return false, time.Since(start), fmt.Errorf("probe unexpectedly succeeded (MustNot)")
+			}
+			select {
+			case <-ctx.Done():
+				return true, time.Since(start), nil
+			case <-ticker.C:
+			}
+		}
+	}
+
+	cfg := backoff.Config{Attempts: 1000, Backoff: assertionPollInterval, Factor: 1, MaxBackoff: 5 * time.Second}
+	perr := backoff.Retry(ctx, cfg, func() error { return a.Probe.Check(ctx, r) })
+	return perr == nil, time.Since(start), perr
+}
+
+// printAssertions writes a PASS/FAIL summary table to stderr, alongside
+// klog's own output, so --output=json's NDJSON event stream on stdout
+// stays machine-parseable.
+func printAssertions(results []assertionResult) {
+	w := tabwriter.NewWriter(os.Stderr, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tNAME\tDURATION")
+	for _, res := range results {
+		status := "PASS"
+		if !res.Pass {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", status, res.Name, res.Duration.Round(time.Millisecond))
+	}
+	w.Flush()
+}
+
+// runLocal executes cmd on the krunk host itself (as opposed to on a
+// cluster node via the Runner), logging its outcome the same way the
+// Runner implementations do.
+func runLocal(cmd *exec.Cmd, stdin io.Reader) (*runner.RunResult, error) {
+	rr := &runner.RunResult{Args: cmd.Args}
 
 	var outb, errb bytes.Buffer
 	cmd.Stdout, rr.Stdout = &outb, &outb
 	cmd.Stderr, rr.Stderr = &errb, &errb
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
 
 	start := time.Now()
 	klog.V(1).Infof("Running: %s", cmd)
@@ -81,110 +322,163 @@ func Run(cmd *exec.Cmd) (*RunResult, error) {
 	}
 
 	if err == nil {
-		return rr, err
+		return rr, nil
 	}
 	return rr, fmt.Errorf("%s: %w, stderr=%s", cmd.Args, err, errb.String())
 }
 
-func runStep(s Step, d time.Duration) error {
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, d)
-	defer cancel()
-
-	cmd := ""
-	args := []string{}
-
-	klog.V(1).Infof("STEP: %+v", s)
-	switch {
-	case s.Local != "":
-		cmd = "sh"
-		args = []string{"-c", s.Local}
-	case s.ControlPlane != "":
-		// TODO: Add support for other environments
-		cmd = "minikube"
-		args = []string{"ssh", s.ControlPlane}
-	case s.Transfer.Source != "":
-		t := s.Transfer
-		cmd = "minikube"
-		// TODO: Add support for non-cp transfers
-		target := "minikube"
-		args = []string{"cp", t.Source, fmt.Sprintf("%s:%s", target, t.Dest)}
-	}
-
-	_, err := Run(exec.CommandContext(ctx, cmd, args...))
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func ensureRequirements(r Requirements, d time.Duration) error {
-	klog.Infof("Ensuring requirements are met: %+v", r)
+func ensureRequirements(r runner.Runner, req runner.Requirements, d time.Duration) error {
+	klog.Infof("Ensuring requirements are met: %+v", req)
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, d)
 	defer cancel()
 
-	// TODO: Add support for other environments
-	args := []string{"minikube", "start", "--kubernetes-version", r.KubernetesVersion}
-	klog.Infof("Setting up cluster: %v", args)
-	_, err := Run(exec.CommandContext(ctx, args[0], args[1:]...))
-	return err
+	return r.Start(ctx, req)
 }
 
 func main() {
 	klog.InitFlags(nil)
 	flag.Parse()
 
+	var emitter event.Emitter = event.Discard
+	if *outputFlag == "json" {
+		emitter = event.NewJSON(os.Stdout)
+	}
+
 	if *sceneFlag == "" {
-		klog.Exitf("--scene is a required flag. Try scenarios/005, for example")
+		exit.Error(reason.SceneParse, "no scene given", errors.New("--scene is a required flag. Try scenarios/005, for example"))
 	}
 
 	f, err := ioutil.ReadFile(filepath.Join(*sceneFlag, "scene.yaml"))
 	if err != nil {
-		klog.Exitf("readfile failed: %v", err)
+		exit.Error(reason.SceneParse, "readfile failed", err)
 	}
 
 	if err := os.Chdir(filepath.Dir(*sceneFlag)); err != nil {
-		klog.Exitf("chdir failed: %v", err)
+		exit.Error(reason.SceneParse, "chdir failed", err)
 	}
 
 	s := &Scenario{}
-	err = yaml.Unmarshal(f, &s)
+	if err := yaml.Unmarshal(f, &s); err != nil {
+		exit.Error(reason.SceneParse, "unmarshal failed", err)
+	}
+
+	emitter.Emit(event.Event{Type: event.TypeSceneStart, Message: *sceneFlag, Total: len(s.Setup)})
+
+	var remoteWorkers []string
+	if *remoteWorkersFlag != "" {
+		remoteWorkers = strings.Split(*remoteWorkersFlag, ",")
+	}
+
+	r, err := runner.New(*targetFlag, runner.Config{
+		RemoteHost:    *remoteHostFlag,
+		RemoteUser:    *remoteUserFlag,
+		RemoteKey:     *remoteKeyFlag,
+		RemoteWorkers: remoteWorkers,
+	})
 	if err != nil {
-		klog.Exitf("unmarshal: %w", err)
+		exit.Error(reason.ClusterProvision, "unsupported target", err)
 	}
 
-	if err := ensureRequirements(s.Requirements, *timeoutFlag); err != nil {
-		klog.Errorf("unable to meet requirements: %v", err)
-		os.Exit(1)
+	if err := ensureRequirements(r, s.Requirements, *timeoutFlag); err != nil {
+		exit.Error(reason.ClusterProvision, "unable to meet requirements", err)
 	}
+	emitter.Emit(event.Event{Type: event.TypeRequirementsMet})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	backgrounded := 0
+	total := len(s.Setup)
+	nodes := make([]scheduler.Node, total)
+	hasBackground := false
 	for i, step := range s.Setup {
-		klog.Infof("Running step %d of %d ...", i, len(s.Setup))
+		i, step := i, step
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("step-%d", i)
+		}
 		if step.Background {
-			go func() {
-				klog.V(1).Infof("Running in background: %+v", step)
-				err := runStep(step, *timeoutFlag)
+			hasBackground = true
+		}
+		nodes[i] = scheduler.Node{
+			Name:       name,
+			DependsOn:  step.DependsOn,
+			Background: step.Background,
+			Run: func(ctx context.Context) error {
+				klog.Infof("Running step %q (%d of %d) ...", name, i+1, total)
+				emitter.Emit(event.Event{Type: event.TypeStepStart, Step: i, Total: total, Message: name})
+
+				rr, k, err := runStep(ctx, r, step, *timeoutFlag)
+				ev := event.Event{Type: event.TypeStepEnd, Step: i, Total: total, Message: name}
+				if rr != nil {
+					res := event.ResultOf(rr)
+					ev.Result = &res
+				}
 				if err != nil {
-					klog.Errorf("background step %d failed: %v", i, err)
+					if step.Background {
+						ev.Type = event.TypeBackgroundFailed
+						k = reason.BackgroundFailed
+					}
+					ev.Err = err.Error()
+					emitter.Emit(ev)
+					return &stepError{kind: k, err: err}
 				}
-			}()
-			backgrounded++
-			continue
+				emitter.Emit(ev)
+				return nil
+			},
 		}
+	}
 
-		err := runStep(step, *timeoutFlag)
-		if err != nil {
-			klog.Errorf("step %d failed: %v", i, err)
-			os.Exit(2)
+	if hasBackground {
+		klog.Infof("Scenario has background steps; hit Ctrl-C to abort once setup completes.")
+	}
+
+	// checkAssertions runs once every foreground step has succeeded, before
+	// Run starts waiting on any still-running background steps -- which may
+	// never return on their own, so assertions can't wait for Run itself.
+	checkAssertions := func() error {
+		if len(s.Assertions) == 0 {
+			return nil
 		}
+		klog.Infof("Setup complete; checking %d assertion(s) ...", len(s.Assertions))
+		results := runAssertions(ctx, r, s.Assertions, *timeoutFlag)
+		printAssertions(results)
+
+		var failed []string
+		for _, res := range results {
+			ev := event.Event{Type: event.TypeAssertionEnd, Message: res.Name}
+			if !res.Pass {
+				ev.Err = res.Err.Error()
+				failed = append(failed, res.Name)
+			}
+			emitter.Emit(ev)
+		}
+		if len(failed) > 0 {
+			return &stepError{kind: reason.AssertionFailed, err: fmt.Errorf("assertions failed: %s", strings.Join(failed, ", "))}
+		}
+		return nil
 	}
 
-	if backgrounded > 0 {
-		klog.Infof("Scenario is live! Hit Ctrl-C to abort.")
-		for {
-			time.Sleep(1 * time.Second)
+	runErr := scheduler.Run(ctx, nodes, *parallelismFlag, checkAssertions)
+
+	if *teardownFlag {
+		if derr := r.Delete(context.Background()); derr != nil {
+			klog.Errorf("teardown failed: %v", derr)
+		}
+	}
+
+	if errors.Is(runErr, context.Canceled) {
+		klog.Infof("Interrupted, exiting.")
+		return
+	}
+	if runErr != nil {
+		k := reason.StepLocal
+		var se *stepError
+		if errors.As(runErr, &se) {
+			k = se.kind
 		}
+		exit.Error(k, "scenario failed", runErr)
 	}
+
+	emitter.Emit(event.Event{Type: event.TypeSummary, Message: "setup complete", Total: total})
 }
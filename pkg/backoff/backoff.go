@@ -0,0 +1,70 @@
+// Package backoff implements exponential backoff with jitter, in the
+// spirit of cenkalti/backoff, for steps that need to poll something until
+// it becomes ready.
+package backoff
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Config describes a backoff schedule.
+type Config struct {
+	// Attempts is the maximum number of tries. Values below 1 are
+	// treated as 1 (try exactly once, no retrying).
+	Attempts int
+	// Backoff is the delay before the second attempt.
+	Backoff time.Duration
+	// Factor multiplies the delay after every attempt. Values at or
+	// below 1 default to 2 (classic doubling).
+	Factor float64
+	// MaxBackoff caps the delay between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+}
+
+// delay returns the wait after the given 0-indexed attempt fails (so
+// delay(0) is the wait before the second attempt), with +/-20%
+// jitter so that many parallel retries don't all land on the same tick.
+func (c Config) delay(attempt int) time.Duration {
+	if c.Backoff <= 0 {
+		return 0
+	}
+	factor := c.Factor
+	if factor <= 1 {
+		factor = 2
+	}
+	d := float64(c.Backoff) * math.Pow(factor, float64(attempt))
+	if c.MaxBackoff > 0 && d > float64(c.MaxBackoff) {
+		d = float64(c.MaxBackoff)
+	}
+	jittered := d * (0.8 + 0.4*rand.Float64())
+	return time.Duration(jittered)
+}
+
+// Retry calls fn until it returns nil, ctx is done, or Attempts tries have
+// been made, whichever comes first. It returns the last error from fn (or
+// ctx.Err() if ctx ran out first).
+func Retry(ctx context.Context, c Config, fn func() error) error {
+	attempts := c.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == attempts-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.delay(attempt)):
+		}
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConfigDelayZeroBackoff(t *testing.T) {
+	c := Config{Backoff: 0}
+	if got := c.delay(5); got != 0 {
+		t.Errorf("delay(5) = %v, want 0", got)
+	}
+}
+
+func TestConfigDelayGrowsAndCaps(t *testing.T) {
+	c := Config{Backoff: time.Second, Factor: 2, MaxBackoff: 4 * time.Second}
+
+	d0 := c.delay(0)
+	d1 := c.delay(1)
+	d4 := c.delay(4)
+
+	// attempt 1 should be roughly double attempt 0 (ignoring jitter's +/-20%).
+	if d1 < d0 {
+		t.Errorf("delay(1) = %v should be >= delay(0) = %v", d1, d0)
+	}
+	// The cap is applied before jitter, so the jittered result can run up to
+	// 20% over MaxBackoff.
+	if max := time.Duration(float64(c.MaxBackoff) * 1.2); d4 > max {
+		t.Errorf("delay(4) = %v exceeds MaxBackoff %v by more than jitter allows (%v)", d4, c.MaxBackoff, max)
+	}
+}
+
+func TestRetrySucceedsWithoutRetrying(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), Config{Attempts: 3}, func() error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Retry() = %v, want nil", err)
+	}
+	if calls != 1 {
+		t.Fatalf("fn called %d times, want 1", calls)
+	}
+}
+
+func TestRetryExhaustsAttempts(t *testing.T) {
+	wantErr := errors.New("still not ready")
+	calls := 0
+	err := Retry(context.Background(), Config{Attempts: 3, Backoff: time.Millisecond}, func() error {
+		calls++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("Retry() = %v, want %v", err, wantErr)
+	}
+	if calls != 3 {
+		t.Fatalf("fn called %d times, want 3", calls)
+	}
+}
+
+func TestRetryStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Retry(ctx, Config{Attempts: 100, Backoff: 10 * time.Millisecond}, func() error {
+		calls++
+		if calls == 2 {
+			cancel()
+		}
+		return errors.New("not ready")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Retry() = %v, want context.Canceled", err)
+	}
+	if calls != 2 {
+		t.Fatalf("fn called %d times, want 2", calls)
+	}
+}
+
+func TestRetryTreatsZeroAttemptsAsOne(t *testing.T) {
+	calls := 0
+	_ = Retry(context.Background(), Config{}, func() error {
+		calls++
+		return errors.New("nope")
+	})
+	if calls != 1 {
+		t.Fatalf("fn called %d times, want 1", calls)
+	}
+}
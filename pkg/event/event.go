@@ -0,0 +1,98 @@
+// Package event streams machine-readable scene events, so krunk can be
+// consumed by CI dashboards and other tooling instead of only parsed from
+// klog text.
+package event
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/tstromberg/krunk/pkg/runner"
+)
+
+// Type identifies the kind of event being reported.
+type Type string
+
+const (
+	TypeSceneStart       Type = "scene_start"
+	TypeRequirementsMet  Type = "requirements_met"
+	TypeStepStart        Type = "step_start"
+	TypeStepEnd          Type = "step_end"
+	TypeBackgroundFailed Type = "background_failed"
+	TypeAssertionEnd     Type = "assertion_end"
+	TypeSummary          Type = "summary"
+)
+
+// Result is the JSON-friendly form of a runner.RunResult.
+type Result struct {
+	Stdout     string   `json:"stdout,omitempty"`
+	Stderr     string   `json:"stderr,omitempty"`
+	ExitCode   int      `json:"exit_code"`
+	DurationMS int64    `json:"duration_ms"`
+	Args       []string `json:"args,omitempty"`
+}
+
+// ResultOf converts a runner.RunResult into its JSON-friendly form. It
+// returns the zero Result if rr is nil.
+func ResultOf(rr *runner.RunResult) Result {
+	if rr == nil {
+		return Result{}
+	}
+	return Result{
+		Stdout:     rr.Stdout.String(),
+		Stderr:     rr.Stderr.String(),
+		ExitCode:   rr.ExitCode,
+		DurationMS: rr.Duration.Milliseconds(),
+		Args:       rr.Args,
+	}
+}
+
+// Event is a single point-in-time fact about scene execution.
+type Event struct {
+	Type    Type      `json:"type"`
+	Time    time.Time `json:"time"`
+	Step    int       `json:"step,omitempty"`
+	Total   int       `json:"total,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Err     string    `json:"error,omitempty"`
+	Result  *Result   `json:"result,omitempty"`
+}
+
+// Emitter streams scene events as they occur.
+type Emitter interface {
+	Emit(e Event)
+}
+
+// discardEmitter drops every event. It's used for --output=text, since
+// klog already reports progress in human-readable form.
+type discardEmitter struct{}
+
+func (discardEmitter) Emit(Event) {}
+
+// Discard is the Emitter used when no machine-readable output was requested.
+var Discard Emitter = discardEmitter{}
+
+// jsonEmitter writes one JSON object per line to an underlying writer, in
+// the order Emit is called.
+type jsonEmitter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSON returns an Emitter that writes newline-delimited JSON to w.
+func NewJSON(w io.Writer) Emitter {
+	return &jsonEmitter{enc: json.NewEncoder(w)}
+}
+
+func (j *jsonEmitter) Emit(e Event) {
+	e.Time = time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.enc.Encode(e); err != nil {
+		klog.Errorf("failed to encode event: %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+// Package exit provides a single place to terminate krunk with a
+// reason.Kind, so every fatal error is reported and coded consistently.
+package exit
+
+import (
+	"os"
+
+	"k8s.io/klog/v2"
+
+	"github.com/tstromberg/krunk/pkg/reason"
+)
+
+// Error logs msg and err along with k's advice, then terminates the
+// process with k's exit code.
+func Error(k reason.Kind, msg string, err error) {
+	klog.Errorf("%s: %s: %v (%s)", k.ID, msg, err, k.Advice)
+	os.Exit(k.ExitCode)
+}
@@ -0,0 +1,192 @@
+// Package probe checks whether some condition of a running scene has been
+// reached yet, so that steps can express "wait until X" instead of an
+// inline `sh -c 'until ...; do sleep 1; done'`.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tstromberg/krunk/pkg/runner"
+)
+
+// HTTP waits for url to respond with the given status (default 200) and,
+// if Body is non-empty, for the response body to contain it.
+type HTTP struct {
+	URL    string
+	Status int
+	Body   string
+}
+
+// Exec waits for cmd to exit with ExpectExit (default 0) when run on Node.
+type Exec struct {
+	Node       string
+	Cmd        string
+	ExpectExit int `yaml:"expect-exit"`
+}
+
+// Kube waits for resource (e.g. "deployment/coredns") in namespace to
+// report the given status condition, via `kubectl wait`.
+type Kube struct {
+	Resource  string
+	Namespace string
+	Condition string
+}
+
+// KubeGet waits for `kubectl get resource -o jsonpath=JSONPath` to match
+// the regular expression Match.
+type KubeGet struct {
+	Resource  string
+	Namespace string
+	JSONPath  string `yaml:"jsonpath"`
+	Match     string
+}
+
+// LogGrep waits for Pattern, a regular expression, to appear in Pod's
+// logs.
+type LogGrep struct {
+	Pod       string
+	Namespace string
+	Container string
+	Pattern   string
+}
+
+// Probe is satisfied by exactly one of HTTP, Exec, Kube, KubeGet, or
+// LogGrep.
+type Probe struct {
+	HTTP    *HTTP
+	Exec    *Exec
+	Kube    *Kube
+	KubeGet *KubeGet
+	LogGrep *LogGrep
+}
+
+// IsZero reports whether no variant is set, i.e. there's nothing to check.
+func (p Probe) IsZero() bool {
+	return p.HTTP == nil && p.Exec == nil && p.Kube == nil && p.KubeGet == nil && p.LogGrep == nil
+}
+
+// Check runs the one variant that's set and returns nil once it's
+// satisfied. r is used to reach Exec.Node through the active backend.
+func (p Probe) Check(ctx context.Context, r runner.Runner) error {
+	switch {
+	case p.HTTP != nil:
+		return p.HTTP.check(ctx)
+	case p.Exec != nil:
+		return p.Exec.check(ctx, r)
+	case p.Kube != nil:
+		return p.Kube.check(ctx)
+	case p.KubeGet != nil:
+		return p.KubeGet.check(ctx)
+	case p.LogGrep != nil:
+		return p.LogGrep.check(ctx)
+	default:
+		return nil
+	}
+}
+
+func (h *HTTP) check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", h.URL, err)
+	}
+	defer resp.Body.Close()
+
+	wantStatus := h.Status
+	if wantStatus == 0 {
+		wantStatus = http.StatusOK
+	}
+	if resp.StatusCode != wantStatus {
+		return fmt.Errorf("GET %s: got status %d, want %d", h.URL, resp.StatusCode, wantStatus)
+	}
+
+	if h.Body == "" {
+		return nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading body of %s: %w", h.URL, err)
+	}
+	if !strings.Contains(string(body), h.Body) {
+		return fmt.Errorf("GET %s: body does not contain %q", h.URL, h.Body)
+	}
+	return nil
+}
+
+func (e *Exec) check(ctx context.Context, r runner.Runner) error {
+	rr, err := r.RunOn(ctx, e.Node, e.Cmd, nil)
+	// A nonzero exit is reported by RunOn as an error too; only errors
+	// that produced no exit code mean the command couldn't be run at all.
+	if err != nil && (rr == nil || rr.ExitCode == 0) {
+		return err
+	}
+	if rr.ExitCode != e.ExpectExit {
+		return fmt.Errorf("%q on %s: exit %d, want %d", e.Cmd, e.Node, rr.ExitCode, e.ExpectExit)
+	}
+	return nil
+}
+
+func (k *Kube) check(ctx context.Context) error {
+	args := []string{"wait", "--for", "condition=" + k.Condition, k.Resource, "--timeout=1s"}
+	if k.Namespace != "" {
+		args = append(args, "--namespace", k.Namespace)
+	}
+	out, err := exec.CommandContext(ctx, "kubectl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("kubectl %s: %w: %s", strings.Join(args, " "), err, out)
+	}
+	return nil
+}
+
+func (g *KubeGet) check(ctx context.Context) error {
+	args := []string{"get", g.Resource, "-o", "jsonpath=" + g.JSONPath}
+	if g.Namespace != "" {
+		args = append(args, "--namespace", g.Namespace)
+	}
+	out, err := exec.CommandContext(ctx, "kubectl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("kubectl %s: %w: %s", strings.Join(args, " "), err, out)
+	}
+	matched, err := regexp.MatchString(g.Match, string(out))
+	if err != nil {
+		return fmt.Errorf("invalid match pattern %q: %w", g.Match, err)
+	}
+	if !matched {
+		return fmt.Errorf("kubectl %s: output %q does not match %q", strings.Join(args, " "), out, g.Match)
+	}
+	return nil
+}
+
+func (l *LogGrep) check(ctx context.Context) error {
+	args := []string{"logs", l.Pod}
+	if l.Namespace != "" {
+		args = append(args, "--namespace", l.Namespace)
+	}
+	if l.Container != "" {
+		args = append(args, "-c", l.Container)
+	}
+	out, err := exec.CommandContext(ctx, "kubectl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("kubectl %s: %w: %s", strings.Join(args, " "), err, out)
+	}
+	matched, err := regexp.MatchString(l.Pattern, string(out))
+	if err != nil {
+		return fmt.Errorf("invalid log pattern %q: %w", l.Pattern, err)
+	}
+	if !matched {
+		return fmt.Errorf("logs for %s do not match %q", l.Pod, l.Pattern)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Package reason assigns every failure krunk can produce a stable code, an
+// exit status, and a short piece of advice, so a CI log (or a human)
+// doesn't have to guess what to do about it. Modeled after minikube's
+// pkg/minikube/reason package.
+package reason
+
+// Kind describes a class of failure.
+type Kind struct {
+	// ID is a short, stable, machine-greppable name for this failure.
+	ID string
+	// ExitCode is the process exit status used when this Kind is fatal.
+	ExitCode int
+	// Advice is a one-line suggestion shown alongside the error.
+	Advice string
+}
+
+var (
+	// SceneParse covers failures to locate, read, or unmarshal scene.yaml.
+	SceneParse = Kind{ID: "SceneParse", ExitCode: 2, Advice: "check that --scene points at a directory containing a valid scene.yaml"}
+	// ClusterProvision covers failures to meet a scene's Requirements.
+	ClusterProvision = Kind{ID: "ClusterProvision", ExitCode: 3, Advice: "check that the --target backend's CLI is installed and the requirements are satisfiable"}
+	// StepLocal covers failures of a Local step run on the krunk host.
+	StepLocal = Kind{ID: "StepLocal", ExitCode: 4, Advice: "the step's local command failed; see its output above"}
+	// StepSSH covers failures of a step run on a cluster node.
+	StepSSH = Kind{ID: "StepSSH", ExitCode: 5, Advice: "the step's command failed on the target node; see its output above"}
+	// StepTransfer covers failures to copy a file to a cluster node.
+	StepTransfer = Kind{ID: "StepTransfer", ExitCode: 6, Advice: "the file transfer failed; check the source and destination paths"}
+	// TimeoutExceeded covers a step that didn't finish within --timeout,
+	// or whose Probe was never satisfied.
+	TimeoutExceeded = Kind{ID: "TimeoutExceeded", ExitCode: 7, Advice: "increase --timeout or speed up the step"}
+	// BackgroundFailed covers a Background: true step failing after the
+	// scenario was already reported live.
+	BackgroundFailed = Kind{ID: "BackgroundFailed", ExitCode: 8, Advice: "a background step failed; the scenario is no longer in its expected state"}
+	// AssertionFailed covers a Must (or MustNot) Assertion that didn't hold
+	// once Setup completed.
+	AssertionFailed = Kind{ID: "AssertionFailed", ExitCode: 9, Advice: "the scene did not reach its expected end state; see the assertion summary above"}
+)
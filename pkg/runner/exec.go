@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// run executes cmd, optionally feeding it stdin, and captures the result.
+func run(cmd *exec.Cmd, stdin io.Reader) (*RunResult, error) {
+	rr := &RunResult{Args: cmd.Args}
+
+	var outb, errb bytes.Buffer
+	cmd.Stdout, rr.Stdout = &outb, &outb
+	cmd.Stderr, rr.Stderr = &errb, &errb
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+
+	start := time.Now()
+	klog.V(1).Infof("Running: %s", cmd)
+	err := cmd.Run()
+	rr.Duration = time.Since(start)
+
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			rr.ExitCode = exitError.ExitCode()
+		}
+		klog.Errorf("cmd.Run returned error: %v", err)
+	}
+
+	klog.V(1).Infof("Completed: %s (duration: %s, exit code: %d, err: %v)", cmd, rr.Duration, rr.ExitCode, err)
+	if len(rr.Stderr.Bytes()) > 0 {
+		klog.Warningf("%s", rr.Stderr.String())
+	}
+
+	if err == nil {
+		return rr, nil
+	}
+	return rr, fmt.Errorf("%s: %w, stderr=%s", cmd.Args, err, errb.String())
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// K3dRunner drives a k3d (k3s-in-Docker) cluster via the k3d CLI.
+type K3dRunner struct {
+	// ClusterName is the k3d cluster name. Defaults to "k3s-default".
+	ClusterName string
+}
+
+func (r *K3dRunner) clusterName() string {
+	if r.ClusterName != "" {
+		return r.ClusterName
+	}
+	return "k3s-default"
+}
+
+// Start creates a k3d cluster matching req.
+func (r *K3dRunner) Start(ctx context.Context, req Requirements) error {
+	args := []string{"cluster", "create", r.clusterName()}
+	if req.Workers > 0 {
+		args = append(args, "--agents", strconv.Itoa(req.Workers))
+	}
+	if req.ControlPlanes > 1 {
+		args = append(args, "--servers", strconv.Itoa(req.ControlPlanes))
+	}
+	if req.KubernetesVersion != "" {
+		args = append(args, "--image", "rancher/k3s:"+req.KubernetesVersion+"-k3s1")
+	}
+	_, err := run(exec.CommandContext(ctx, "k3d", args...), nil)
+	return err
+}
+
+// RunOn executes cmd inside the named k3d node via `docker exec`, since
+// k3d has no native exec subcommand (its "node" group only has
+// create/delete/edit/list/start/stop).
+func (r *K3dRunner) RunOn(ctx context.Context, node string, cmd string, stdin io.Reader) (*RunResult, error) {
+	if node == "" {
+		node = fmt.Sprintf("k3d-%s-server-0", r.clusterName())
+	}
+	return run(exec.CommandContext(ctx, "docker", "exec", "-i", node, "sh", "-c", cmd), stdin)
+}
+
+// Copy transfers src into node via `docker cp`, since k3d has no native
+// copy subcommand.
+func (r *K3dRunner) Copy(ctx context.Context, node string, src string, dst string) error {
+	if node == "" {
+		node = fmt.Sprintf("k3d-%s-server-0", r.clusterName())
+	}
+	_, err := run(exec.CommandContext(ctx, "docker", "cp", src, fmt.Sprintf("%s:%s", node, dst)), nil)
+	return err
+}
+
+// ListNodes parses `k3d node list`, filtering to the containers that
+// belong to this cluster and classifying them by their "-server-" /
+// "-agent-" name segment.
+func (r *K3dRunner) ListNodes(ctx context.Context) ([]Node, error) {
+	rr, err := run(exec.CommandContext(ctx, "k3d", "node", "list", "--no-headers"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("k3d-%s-", r.clusterName())
+	var nodes []Node
+	controlIdx, workerIdx := 0, 0
+	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		name := fields[0]
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		switch {
+		case strings.Contains(name, "-server-"):
+			nodes = append(nodes, Node{Name: name, Role: "control-plane", Index: controlIdx})
+			controlIdx++
+		case strings.Contains(name, "-agent-"):
+			nodes = append(nodes, Node{Name: name, Role: "worker", Index: workerIdx})
+			workerIdx++
+		}
+	}
+	return nodes, nil
+}
+
+// Delete runs `k3d cluster delete` to tear down the cluster.
+func (r *K3dRunner) Delete(ctx context.Context) error {
+	_, err := run(exec.CommandContext(ctx, "k3d", "cluster", "delete", r.clusterName()), nil)
+	return err
+}
@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// KindRunner drives a kind (Kubernetes-in-Docker) cluster. Nodes are
+// addressed by their kind container name, e.g. "kind-worker", "kind-worker2".
+type KindRunner struct {
+	// ClusterName is the kind cluster name. Defaults to "kind".
+	ClusterName string
+}
+
+func (r *KindRunner) clusterName() string {
+	if r.ClusterName != "" {
+		return r.ClusterName
+	}
+	return "kind"
+}
+
+// Start creates a kind cluster matching req. Multi-node topologies are
+// expressed via a generated kind config, since kind has no flag for node
+// counts.
+func (r *KindRunner) Start(ctx context.Context, req Requirements) error {
+	args := []string{"create", "cluster", "--name", r.clusterName()}
+	if req.KubernetesVersion != "" {
+		args = append(args, "--image", "kindest/node:"+req.KubernetesVersion)
+	}
+
+	if req.ControlPlanes > 1 || req.Workers > 0 {
+		cfg, err := writeKindConfig(req)
+		if err != nil {
+			return fmt.Errorf("writing kind config: %w", err)
+		}
+		defer os.Remove(cfg)
+		args = append(args, "--config", cfg)
+	}
+
+	_, err := run(exec.CommandContext(ctx, "kind", args...), nil)
+	return err
+}
+
+// writeKindConfig renders a minimal kind cluster config describing req's
+// control-plane and worker node counts, and returns the path it was
+// written to.
+func writeKindConfig(req Requirements) (string, error) {
+	var b strings.Builder
+	b.WriteString("kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnodes:\n")
+	controlPlanes := req.ControlPlanes
+	if controlPlanes < 1 {
+		controlPlanes = 1
+	}
+	for i := 0; i < controlPlanes; i++ {
+		b.WriteString("- role: control-plane\n")
+	}
+	for i := 0; i < req.Workers; i++ {
+		b.WriteString("- role: worker\n")
+	}
+
+	f, err := ioutil.TempFile("", "krunk-kind-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(b.String()); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// RunOn executes cmd inside the named kind node container via `docker exec`.
+func (r *KindRunner) RunOn(ctx context.Context, node string, cmd string, stdin io.Reader) (*RunResult, error) {
+	if node == "" {
+		node = r.clusterName() + "-control-plane"
+	}
+	args := []string{"exec", "-i", node, "sh", "-c", cmd}
+	return run(exec.CommandContext(ctx, "docker", args...), stdin)
+}
+
+// Copy transfers src into node via `docker cp`.
+func (r *KindRunner) Copy(ctx context.Context, node string, src string, dst string) error {
+	if node == "" {
+		node = r.clusterName() + "-control-plane"
+	}
+	_, err := run(exec.CommandContext(ctx, "docker", "cp", src, fmt.Sprintf("%s:%s", node, dst)), nil)
+	return err
+}
+
+// ListNodes parses `kind get nodes`, which prints one container name per
+// line (e.g. "kind-control-plane", "kind-worker", "kind-worker2").
+func (r *KindRunner) ListNodes(ctx context.Context) ([]Node, error) {
+	rr, err := run(exec.CommandContext(ctx, "kind", "get", "nodes", "--name", r.clusterName()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []Node
+	controlIdx, workerIdx := 0, 0
+	for _, name := range strings.Fields(rr.Stdout.String()) {
+		switch {
+		case strings.Contains(name, "control-plane"):
+			nodes = append(nodes, Node{Name: name, Role: "control-plane", Index: controlIdx})
+			controlIdx++
+		case strings.Contains(name, "worker"):
+			nodes = append(nodes, Node{Name: name, Role: "worker", Index: workerIdx})
+			workerIdx++
+		}
+	}
+	return nodes, nil
+}
+
+// Delete runs `kind delete cluster` to tear down the cluster.
+func (r *KindRunner) Delete(ctx context.Context) error {
+	_, err := run(exec.CommandContext(ctx, "kind", "delete", "cluster", "--name", r.clusterName()), nil)
+	return err
+}
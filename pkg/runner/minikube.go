@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MinikubeRunner drives a minikube cluster via the minikube CLI.
+type MinikubeRunner struct{}
+
+// Start runs `minikube start` with the given requirements.
+func (r *MinikubeRunner) Start(ctx context.Context, req Requirements) error {
+	args := []string{"start", "--kubernetes-version", req.KubernetesVersion}
+	if req.Workers > 0 {
+		args = append(args, "--nodes", strconv.Itoa(req.Workers+1))
+	}
+	if req.CNI != "" {
+		args = append(args, "--cni", req.CNI)
+	}
+	_, err := run(exec.CommandContext(ctx, "minikube", args...), nil)
+	return err
+}
+
+// RunOn executes cmd on node via `minikube ssh -n`, passing node through
+// as-is; an empty node runs cmd on the primary control plane.
+func (r *MinikubeRunner) RunOn(ctx context.Context, node string, cmd string, stdin io.Reader) (*RunResult, error) {
+	args := []string{"ssh", "--"}
+	if node != "" {
+		args = []string{"ssh", "-n", node, "--"}
+	}
+	args = append(args, cmd)
+	return run(exec.CommandContext(ctx, "minikube", args...), stdin)
+}
+
+// Copy transfers src to dst on node via `minikube cp`.
+func (r *MinikubeRunner) Copy(ctx context.Context, node string, src string, dst string) error {
+	target := "minikube"
+	if node != "" {
+		target = node
+	}
+	_, err := run(exec.CommandContext(ctx, "minikube", "cp", src, target+":"+dst), nil)
+	return err
+}
+
+// ListNodes parses `minikube node list`, whose output is one
+// "<name>\t<ip>" line per node. The first node is always the primary
+// control plane; every node after it is a worker.
+func (r *MinikubeRunner) ListNodes(ctx context.Context) ([]Node, error) {
+	rr, err := run(exec.CommandContext(ctx, "minikube", "node", "list"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []Node
+	workerIdx := 0
+	scanner := bufio.NewScanner(strings.NewReader(rr.Stdout.String()))
+	for scanner.Scan() {
+		name := strings.TrimSpace(strings.SplitN(scanner.Text(), "\t", 2)[0])
+		if name == "" {
+			continue
+		}
+		if len(nodes) == 0 {
+			nodes = append(nodes, Node{Name: name, Role: "control-plane", Index: 0})
+			continue
+		}
+		nodes = append(nodes, Node{Name: name, Role: "worker", Index: workerIdx})
+		workerIdx++
+	}
+	return nodes, nil
+}
+
+// Delete runs `minikube delete` to tear down the cluster.
+func (r *MinikubeRunner) Delete(ctx context.Context) error {
+	_, err := run(exec.CommandContext(ctx, "minikube", "delete"), nil)
+	return err
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// RemoteRunner drives a pre-existing cluster reachable over SSH. It does
+// not provision anything; Start only validates that the control-plane host
+// is reachable. Node names are resolved directly as hostnames, so a step
+// can target any host in Workers (or Host itself) by name.
+type RemoteRunner struct {
+	Host    string
+	User    string
+	Key     string
+	Workers []string
+}
+
+func (r *RemoteRunner) sshArgs(host string) []string {
+	args := []string{"-o", "StrictHostKeyChecking=no"}
+	if r.Key != "" {
+		args = append(args, "-i", r.Key)
+	}
+	target := host
+	if r.User != "" {
+		target = fmt.Sprintf("%s@%s", r.User, host)
+	}
+	return append(args, target)
+}
+
+func (r *RemoteRunner) host(node string) string {
+	if node != "" {
+		return node
+	}
+	return r.Host
+}
+
+// Start validates that the remote host is reachable. RemoteRunner assumes
+// the cluster already satisfies req; it cannot provision one.
+func (r *RemoteRunner) Start(ctx context.Context, req Requirements) error {
+	if r.Host == "" {
+		return fmt.Errorf("remote runner requires --remote-host")
+	}
+	args := append(r.sshArgs(r.Host), "true")
+	_, err := run(exec.CommandContext(ctx, "ssh", args...), nil)
+	return err
+}
+
+// RunOn executes cmd on node over SSH.
+func (r *RemoteRunner) RunOn(ctx context.Context, node string, cmd string, stdin io.Reader) (*RunResult, error) {
+	args := append(r.sshArgs(r.host(node)), cmd)
+	return run(exec.CommandContext(ctx, "ssh", args...), stdin)
+}
+
+// Copy transfers src to dst on node via scp.
+func (r *RemoteRunner) Copy(ctx context.Context, node string, src string, dst string) error {
+	args := []string{"-o", "StrictHostKeyChecking=no"}
+	if r.Key != "" {
+		args = append(args, "-i", r.Key)
+	}
+	host := r.host(node)
+	target := host
+	if r.User != "" {
+		target = fmt.Sprintf("%s@%s", r.User, host)
+	}
+	args = append(args, src, fmt.Sprintf("%s:%s", target, dst))
+	_, err := run(exec.CommandContext(ctx, "scp", args...), nil)
+	return err
+}
+
+// ListNodes returns Host as the sole control-plane node and Workers as
+// worker nodes. RemoteRunner has no way to discover cluster topology on
+// its own, so these must be supplied via --remote-host/--remote-workers.
+func (r *RemoteRunner) ListNodes(ctx context.Context) ([]Node, error) {
+	var nodes []Node
+	if r.Host != "" {
+		nodes = append(nodes, Node{Name: r.Host, Role: "control-plane", Index: 0})
+	}
+	for i, w := range r.Workers {
+		nodes = append(nodes, Node{Name: w, Role: "worker", Index: i})
+	}
+	return nodes, nil
+}
+
+// Delete always fails: RemoteRunner targets a pre-existing cluster it
+// doesn't own, so it has nothing to tear down.
+func (r *RemoteRunner) Delete(ctx context.Context) error {
+	return fmt.Errorf("remote clusters are not managed by krunk; tear it down yourself")
+}
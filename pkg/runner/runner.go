@@ -0,0 +1,83 @@
+// Package runner abstracts the differences between the cluster backends
+// that krunk can drive (minikube, kind, k3d, or a pre-existing remote
+// cluster reachable over SSH), so that scene steps can be written once and
+// executed against any of them.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Requirements describes the requirements of the target cluster.
+type Requirements struct {
+	KubernetesVersion string `yaml:"kubernetes-version"`
+	ControlPlanes     int    `yaml:"control-planes"`
+	Workers           int
+	CNI               string
+}
+
+// RunResult stores the result of a command execution on a node.
+type RunResult struct {
+	Stdout   *bytes.Buffer
+	Stderr   *bytes.Buffer
+	ExitCode int
+	Duration time.Duration
+	Args     []string
+}
+
+// Runner is implemented by each supported cluster backend. Implementations
+// are expected to be safe to reuse across many steps within a scene.
+type Runner interface {
+	// Start provisions (or validates) a cluster that satisfies r.
+	Start(ctx context.Context, r Requirements) error
+	// RunOn executes cmd on node, feeding it stdin if non-nil.
+	RunOn(ctx context.Context, node string, cmd string, stdin io.Reader) (*RunResult, error)
+	// Copy transfers src on the krunk host to dst on node.
+	Copy(ctx context.Context, node string, src string, dst string) error
+	// ListNodes returns the nodes of the running cluster, so that scenes
+	// can target them with a NodeSelector rather than a fixed name.
+	ListNodes(ctx context.Context) ([]Node, error)
+	// Delete tears down the cluster that Start created. Backends that
+	// don't own their cluster's lifecycle (RemoteRunner) return an error.
+	Delete(ctx context.Context) error
+}
+
+// Node describes a single node of the cluster, as reported by ListNodes.
+type Node struct {
+	Name string
+	// Role is "control-plane" or "worker".
+	Role string
+	// Index is the node's position among nodes of the same Role, starting
+	// at 0, so selectors like "index=1" can address "the second worker".
+	Index int
+}
+
+// Config carries the flags needed to construct any of the Runner
+// implementations. Fields that don't apply to a given backend are ignored.
+type Config struct {
+	RemoteHost    string
+	RemoteUser    string
+	RemoteKey     string
+	RemoteWorkers []string
+}
+
+// New returns the Runner for the named target ("minikube", "kind", "k3d",
+// or "remote").
+func New(target string, c Config) (Runner, error) {
+	switch target {
+	case "minikube":
+		return &MinikubeRunner{}, nil
+	case "kind":
+		return &KindRunner{}, nil
+	case "k3d":
+		return &K3dRunner{}, nil
+	case "remote":
+		return &RemoteRunner{Host: c.RemoteHost, User: c.RemoteUser, Key: c.RemoteKey, Workers: c.RemoteWorkers}, nil
+	default:
+		return nil, fmt.Errorf("unknown target: %q", target)
+	}
+}
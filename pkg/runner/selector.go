@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SelectNodes resolves a NodeSelector against the cluster's current nodes.
+// Supported forms:
+//
+//	"all-workers"        every worker node
+//	"all-control-planes" every control-plane node
+//	"role=worker"        every node with the given role
+//	"index=1"            the node at that index within its role group,
+//	                      scoped to workers unless combined with a role
+//	                      (e.g. "role=control-plane,index=0")
+//	a bare name          the single node with that name
+func SelectNodes(nodes []Node, selector string) ([]Node, error) {
+	switch selector {
+	case "all-workers":
+		return nonEmpty(filterRole(nodes, "worker"), selector)
+	case "all-control-planes":
+		return nonEmpty(filterRole(nodes, "control-plane"), selector)
+	}
+
+	role := ""
+	index := -1
+	for _, part := range strings.Split(selector, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "role":
+			role = kv[1]
+		case "index":
+			i, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index in selector %q: %w", selector, err)
+			}
+			index = i
+		}
+	}
+
+	if role == "" && index == -1 {
+		for _, n := range nodes {
+			if n.Name == selector {
+				return []Node{n}, nil
+			}
+		}
+		return nil, fmt.Errorf("no node matches selector %q", selector)
+	}
+
+	if role == "" {
+		role = "worker"
+	}
+	matched := filterRole(nodes, role)
+	if index == -1 {
+		return nonEmpty(matched, selector)
+	}
+	for _, n := range matched {
+		if n.Index == index {
+			return []Node{n}, nil
+		}
+	}
+	return nil, fmt.Errorf("no %s node at index %d (selector %q)", role, index, selector)
+}
+
+func filterRole(nodes []Node, role string) []Node {
+	var out []Node
+	for _, n := range nodes {
+		if n.Role == role {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// nonEmpty turns a zero-match selector into an error instead of a silent
+// no-op step, so a typo'd role or an empty cluster fails loudly rather
+// than being reported as a clean success.
+func nonEmpty(nodes []Node, selector string) ([]Node, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no node matches selector %q", selector)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,215 @@
+// Package scheduler runs a DAG of named steps, respecting dependencies
+// and an overall parallelism cap, so a scene can express "step C waits on
+// A and B, but A and B run concurrently" instead of one strictly serial
+// list.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Node is a single unit of scheduled work.
+type Node struct {
+	// Name uniquely identifies this node so other nodes can DependsOn it.
+	Name string
+	// DependsOn lists the Names that must finish successfully before
+	// this node starts.
+	DependsOn []string
+	// Background nodes don't block scene completion: once started, they
+	// keep running, and the scheduler reports their failure (whenever it
+	// occurs) only once every foreground node has finished.
+	Background bool
+	// Run does the node's actual work. It should respect ctx cancellation.
+	Run func(ctx context.Context) error
+}
+
+// Run executes nodes to completion, respecting DependsOn and running at
+// most parallelism of them concurrently (parallelism <= 0 means
+// unlimited). It returns the first foreground failure, canceling every
+// other in-flight node.
+//
+// Once every foreground node has succeeded, onForegroundDone (if non-nil)
+// is called before Run waits on any still-running background nodes; this
+// is the caller's hook for work that must happen right as setup completes
+// (e.g. scene assertions), rather than after background nodes are done
+// too, which may be never. If onForegroundDone returns an error, Run
+// returns it immediately without waiting on background nodes. Otherwise,
+// if there are any background nodes, Run blocks until either one of them
+// fails or ctx is canceled (e.g. by Ctrl-C); background nodes that return
+// nil do not end the wait.
+func Run(ctx context.Context, nodes []Node, parallelism int, onForegroundDone func() error) error {
+	if err := validate(nodes); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if parallelism <= 0 {
+		parallelism = len(nodes)
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+
+	done := make(map[string]chan struct{}, len(nodes))
+	for _, n := range nodes {
+		done[n.Name] = make(chan struct{})
+	}
+
+	var fgWG sync.WaitGroup
+	var mu sync.Mutex
+	var fgErr error
+	bgErrCh := make(chan error, len(nodes))
+	bgRunning := 0
+
+	for _, n := range nodes {
+		n := n
+		if n.Background {
+			bgRunning++
+		} else {
+			fgWG.Add(1)
+		}
+
+		go func() {
+			if !n.Background {
+				defer fgWG.Done()
+			}
+			defer close(done[n.Name])
+
+			for _, dep := range n.DependsOn {
+				select {
+				case <-done[dep]:
+					// A failed dependency cancels ctx before closing its
+					// done channel, so this catches it too.
+					if ctx.Err() != nil {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			sem <- struct{}{}
+			err := n.Run(ctx)
+			<-sem
+
+			if err == nil {
+				return
+			}
+			if n.Background {
+				bgErrCh <- fmt.Errorf("background step %q failed: %w", n.Name, err)
+				return
+			}
+			mu.Lock()
+			if fgErr == nil {
+				fgErr = fmt.Errorf("step %q failed: %w", n.Name, err)
+			}
+			mu.Unlock()
+			cancel()
+		}()
+	}
+
+	fgWG.Wait()
+
+	mu.Lock()
+	err := fgErr
+	mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if onForegroundDone != nil {
+		if err := onForegroundDone(); err != nil {
+			return err
+		}
+	}
+
+	if bgRunning == 0 {
+		return nil
+	}
+	select {
+	case err := <-bgErrCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// validate checks that every DependsOn name refers to a node that exists,
+// names are unique, no node depends on a background node, and dependencies
+// don't form a cycle.
+func validate(nodes []Node) error {
+	seen := make(map[string]bool, len(nodes))
+	background := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		if seen[n.Name] {
+			return fmt.Errorf("duplicate step name %q", n.Name)
+		}
+		seen[n.Name] = true
+		if n.Background {
+			background[n.Name] = true
+		}
+	}
+	for _, n := range nodes {
+		for _, dep := range n.DependsOn {
+			if !seen[dep] {
+				return fmt.Errorf("step %q depends on unknown step %q", n.Name, dep)
+			}
+			// A background node never closes its done channel until it
+			// fails or ctx is canceled, so a dependent would either hang
+			// forever or, on cancellation, return as if it had succeeded
+			// without ever running.
+			if background[dep] {
+				return fmt.Errorf("step %q depends on %q, but %q is a background step with no dependents", n.Name, dep, dep)
+			}
+		}
+	}
+	return detectCycle(nodes)
+}
+
+func detectCycle(nodes []Node) error {
+	byName := make(map[string]Node, len(nodes))
+	for _, n := range nodes {
+		byName[n.Name] = n
+	}
+
+	const (
+		white = iota
+		gray
+		black
+	)
+	state := make(map[string]int, len(nodes))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case black:
+			return nil
+		case gray:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+		state[name] = gray
+		for _, dep := range byName[name].DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = black
+		return nil
+	}
+
+	for _, n := range nodes {
+		if err := visit(n.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunRespectsDependencies(t *testing.T) {
+	var aDone, bStarted int32
+	nodes := []Node{
+		{
+			Name: "a",
+			Run: func(ctx context.Context) error {
+				time.Sleep(10 * time.Millisecond)
+				atomic.StoreInt32(&aDone, 1)
+				return nil
+			},
+		},
+		{
+			Name:      "b",
+			DependsOn: []string{"a"},
+			Run: func(ctx context.Context) error {
+				atomic.StoreInt32(&bStarted, 1)
+				if atomic.LoadInt32(&aDone) == 0 {
+					return errors.New("b started before a finished")
+				}
+				return nil
+			},
+		},
+	}
+	if err := Run(context.Background(), nodes, 0, nil); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	if atomic.LoadInt32(&bStarted) == 0 {
+		t.Fatal("b never ran")
+	}
+}
+
+func TestRunForegroundFailureCancelsOthers(t *testing.T) {
+	started := make(chan struct{})
+	nodes := []Node{
+		{
+			Name: "fails",
+			Run: func(ctx context.Context) error {
+				return errors.New("boom")
+			},
+		},
+		{
+			Name: "waits",
+			Run: func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+	}
+	err := Run(context.Background(), nodes, 0, nil)
+	if err == nil || err.Error() == "" {
+		t.Fatalf("Run() = %v, want a foreground failure", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("waits never started")
+	}
+}
+
+func TestRunCallsOnForegroundDoneBeforeBackgroundWait(t *testing.T) {
+	var onForegroundDoneCalled int32
+	nodes := []Node{
+		{
+			Name:       "bg",
+			Background: true,
+			Run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		{
+			Name: "fg",
+			Run: func(ctx context.Context) error {
+				return nil
+			},
+		},
+	}
+	err := Run(context.Background(), nodes, 0, func() error {
+		atomic.StoreInt32(&onForegroundDoneCalled, 1)
+		return errors.New("assertions failed")
+	})
+	if err == nil || err.Error() != "assertions failed" {
+		t.Fatalf("Run() = %v, want the onForegroundDone error", err)
+	}
+	if atomic.LoadInt32(&onForegroundDoneCalled) == 0 {
+		t.Fatal("onForegroundDone was never called")
+	}
+}
+
+func TestRunWaitsOnBackgroundFailureWhenForegroundDoneSucceeds(t *testing.T) {
+	nodes := []Node{
+		{
+			Name:       "bg",
+			Background: true,
+			Run: func(ctx context.Context) error {
+				return errors.New("background broke")
+			},
+		},
+		{
+			Name: "fg",
+			Run: func(ctx context.Context) error {
+				return nil
+			},
+		},
+	}
+	err := Run(context.Background(), nodes, 0, nil)
+	if err == nil {
+		t.Fatal("Run() = nil, want the background failure")
+	}
+}
+
+func TestValidateRejectsDuplicateNames(t *testing.T) {
+	nodes := []Node{{Name: "a"}, {Name: "a"}}
+	if err := validate(nodes); err == nil {
+		t.Fatal("validate() = nil, want an error for duplicate names")
+	}
+}
+
+func TestValidateRejectsUnknownDependency(t *testing.T) {
+	nodes := []Node{{Name: "a", DependsOn: []string{"missing"}}}
+	if err := validate(nodes); err == nil {
+		t.Fatal("validate() = nil, want an error for an unknown dependency")
+	}
+}
+
+func TestValidateRejectsCycle(t *testing.T) {
+	nodes := []Node{
+		{Name: "a", DependsOn: []string{"b"}},
+		{Name: "b", DependsOn: []string{"a"}},
+	}
+	if err := validate(nodes); err == nil {
+		t.Fatal("validate() = nil, want an error for a dependency cycle")
+	}
+}
+
+func TestValidateRejectsDependencyOnBackgroundNode(t *testing.T) {
+	nodes := []Node{
+		{Name: "bg", Background: true},
+		{Name: "fg", DependsOn: []string{"bg"}},
+	}
+	if err := validate(nodes); err == nil {
+		t.Fatal("validate() = nil, want an error for depending on a background node")
+	}
+}
+
+func TestValidateAllowsIndependentBackgroundNode(t *testing.T) {
+	nodes := []Node{
+		{Name: "bg", Background: true},
+		{Name: "fg"},
+	}
+	if err := validate(nodes); err != nil {
+		t.Fatalf("validate() = %v, want nil", err)
+	}
+}